@@ -0,0 +1,301 @@
+package services
+
+// Multi-format archive extraction for update packages. Release assets may
+// arrive as a zip (Windows), a tar.gz (Linux/macOS), or a bare binary with
+// no wrapper at all, so extraction dispatches on the file's magic bytes
+// rather than trusting its extension. tar.xz is recognized by magic bytes
+// so it fails with a clear error instead of "unsupported archive format",
+// but isn't extracted: the stdlib has no xz decoder and this module has no
+// go.mod to pull in a third-party one.
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+type archiveFormat int
+
+const (
+	formatUnknown archiveFormat = iota
+	formatZip
+	formatTarGz
+	formatTarXz
+	formatBinary
+)
+
+var (
+	zipMagic    = []byte{0x50, 0x4B, 0x03, 0x04}
+	gzipMagic   = []byte{0x1F, 0x8B}
+	xzMagic     = []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}
+	elfMagic    = []byte{0x7F, 'E', 'L', 'F'}
+	peMagic     = []byte{'M', 'Z'}
+	machoMagics = [][]byte{
+		{0xFE, 0xED, 0xFA, 0xCE}, {0xCE, 0xFA, 0xED, 0xFE}, // 32-bit
+		{0xFE, 0xED, 0xFA, 0xCF}, {0xCF, 0xFA, 0xED, 0xFE}, // 64-bit
+		{0xCA, 0xFE, 0xBA, 0xBE}, // fat binary
+	}
+)
+
+// sniffFormat reads the file header and identifies the archive format by
+// magic bytes, independent of the filename/extension.
+func sniffFormat(path string) (archiveFormat, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return formatUnknown, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 8)
+	n, err := f.Read(header)
+	if err != nil && err != io.EOF {
+		return formatUnknown, err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		return formatZip, nil
+	case bytes.HasPrefix(header, xzMagic):
+		return formatTarXz, nil
+	case bytes.HasPrefix(header, gzipMagic):
+		return formatTarGz, nil
+	case bytes.HasPrefix(header, elfMagic), bytes.HasPrefix(header, peMagic):
+		return formatBinary, nil
+	}
+	for _, magic := range machoMagics {
+		if bytes.HasPrefix(header, magic) {
+			return formatBinary, nil
+		}
+	}
+
+	return formatUnknown, fmt.Errorf("unrecognized archive format")
+}
+
+// extractArchive extracts src (a zip, tar.gz, or bare binary) into dst,
+// sniffing the format from the file's content.
+func (u *UpdaterService) extractArchive(src, dst string) error {
+	format, err := sniffFormat(src)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case formatZip:
+		return u.extractZip(src, dst)
+	case formatTarGz:
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		return extractTar(gz, dst)
+	case formatTarXz:
+		return fmt.Errorf("tar.xz assets are not supported for %s", src)
+	case formatBinary:
+		return copyBareBinary(src, dst)
+	default:
+		return fmt.Errorf("unsupported archive format for %s", src)
+	}
+}
+
+// extractZip extracts a zip file to destination directory
+func (u *UpdaterService) extractZip(src, dst string) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cleanDst := filepath.Clean(dst)
+
+	for _, f := range r.File {
+		fpath, err := safeJoin(cleanDst, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().Mode()&os.ModeSymlink != 0 {
+			if err := extractSymlink(f, cleanDst, fpath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if f.FileInfo().IsDir() {
+			os.MkdirAll(fpath, 0755)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+			return err
+		}
+
+		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+		if err != nil {
+			return err
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			outFile.Close()
+			return err
+		}
+
+		_, err = io.Copy(outFile, rc)
+		outFile.Close()
+		rc.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractSymlink reads a zip-stored symlink's target and refuses to
+// materialize it if the target would resolve outside of dst.
+func extractSymlink(f *zip.File, dst, fpath string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	target, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+
+	resolved := filepath.Join(filepath.Dir(fpath), string(target))
+	if !within(dst, resolved) {
+		return fmt.Errorf("symlink %s escapes destination", f.Name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(string(target), fpath)
+}
+
+// extractTar extracts a tar stream (already decompressed) to dst.
+func extractTar(r io.Reader, dst string) error {
+	cleanDst := filepath.Clean(dst)
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		fpath, err := safeJoin(cleanDst, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			os.MkdirAll(fpath, os.FileMode(header.Mode))
+		case tar.TypeSymlink, tar.TypeLink:
+			resolved := filepath.Join(filepath.Dir(fpath), header.Linkname)
+			if !within(cleanDst, resolved) {
+				return fmt.Errorf("symlink %s escapes destination", header.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+				return err
+			}
+			os.Symlink(header.Linkname, fpath)
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+				return err
+			}
+			outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(outFile, tr)
+			outFile.Close()
+			if err != nil {
+				return err
+			}
+			// Preserve the executable bit recorded in the tar header.
+			if runtime.GOOS != "windows" {
+				os.Chmod(fpath, os.FileMode(header.Mode))
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyBareBinary handles a release asset that is the executable itself,
+// with no archive wrapper at all. It is written out as BinaryBaseName (plus
+// ".exe" on Windows) rather than keeping the downloaded asset's own name,
+// so findExtractedBinary can find it the same way it finds an archive's
+// extracted binary.
+func copyBareBinary(src, dst string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+
+	name := BinaryBaseName
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	fpath := filepath.Join(dst, name)
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// safeJoin joins dst and name — the zip-slip hardening, generalized beyond
+// the previous filepath.Base-only check so real subdirectories survive
+// extraction. name is rooted before joining, so ".." path segments collapse
+// into a subpath of dst instead of escaping it; any result that still falls
+// outside dst is rejected.
+func safeJoin(dst, name string) (string, error) {
+	cleanName := filepath.Clean(string(filepath.Separator) + name)
+	fpath := filepath.Join(dst, cleanName)
+	if !within(dst, fpath) {
+		return "", fmt.Errorf("illegal file path in archive: %s", name)
+	}
+	return fpath, nil
+}
+
+// within reports whether path is dst itself or nested inside it.
+func within(dst, path string) bool {
+	dst = filepath.Clean(dst)
+	path = filepath.Clean(path)
+	if path == dst {
+		return true
+	}
+	return strings.HasPrefix(path, dst+string(filepath.Separator))
+}
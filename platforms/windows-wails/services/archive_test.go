@@ -0,0 +1,101 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSniffFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := []struct {
+		name   string
+		header []byte
+		want   archiveFormat
+	}{
+		{"zip", zipMagic, formatZip},
+		{"gzip", gzipMagic, formatTarGz},
+		{"xz", xzMagic, formatTarXz},
+		{"elf", elfMagic, formatBinary},
+		{"pe", peMagic, formatBinary},
+		{"unknown", []byte{0x00, 0x01, 0x02, 0x03}, formatUnknown},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name)
+			if err := os.WriteFile(path, tc.header, 0644); err != nil {
+				t.Fatalf("failed to write test file: %v", err)
+			}
+
+			got, err := sniffFormat(path)
+			if tc.want == formatUnknown {
+				if err == nil {
+					t.Fatal("sniffFormat expected an error for an unrecognized header, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sniffFormat returned error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("sniffFormat() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	dst := "/tmp/fkey-extract"
+
+	// "../" segments are rooted against dst rather than the real filesystem,
+	// so a zip-slip attempt collapses into a harmless subpath of dst instead
+	// of escaping it.
+	escaping, err := safeJoin(dst, "../../etc/passwd")
+	if err != nil {
+		t.Fatalf("safeJoin returned error for %q: %v", "../../etc/passwd", err)
+	}
+	if !within(dst, escaping) {
+		t.Errorf("safeJoin(%q) = %q, escapes dst", "../../etc/passwd", escaping)
+	}
+
+	got, err := safeJoin(dst, "bin/fkey")
+	if err != nil {
+		t.Fatalf("safeJoin returned error for a legitimate subpath: %v", err)
+	}
+	if want := filepath.Join(dst, "bin", "fkey"); got != want {
+		t.Errorf("safeJoin() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractZipRejectsEscapingSymlink(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "update.zip")
+	dst := filepath.Join(dir, "extract")
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	hdr := &zip.FileHeader{Name: "evil-link"}
+	hdr.SetMode(os.ModeSymlink | 0777)
+	fw, err := zw.CreateHeader(hdr)
+	if err != nil {
+		t.Fatalf("failed to write zip entry: %v", err)
+	}
+	if _, err := fw.Write([]byte("../../../../etc")); err != nil {
+		t.Fatalf("failed to write symlink target: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip writer: %v", err)
+	}
+	if err := os.WriteFile(zipPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write test zip: %v", err)
+	}
+
+	u := &UpdaterService{}
+	if err := u.extractZip(zipPath, dst); err == nil {
+		t.Fatal("extractZip expected an error for a symlink escaping dst, got nil")
+	}
+}
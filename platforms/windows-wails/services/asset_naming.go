@@ -0,0 +1,66 @@
+package services
+
+// Asset naming template: lets a release publish one binary per OS/arch and
+// have the updater pick the one matching the machine it's running on,
+// instead of assuming a single portable.zip asset.
+
+import (
+	"bytes"
+	"runtime"
+	"text/template"
+)
+
+// DefaultAssetNameTemplate is the naming scheme new releases are expected
+// to publish under, e.g. "FKey_1.4.0_windows_amd64.zip".
+const DefaultAssetNameTemplate = "FKey_{{.Version}}_{{.GOOS}}_{{.GOARCH}}{{.EXT}}"
+
+// legacyAssetNameTemplate matches the single-asset naming used before
+// per-platform releases existed, kept so older releases still resolve.
+const legacyAssetNameTemplate = "FKey-v{{.Version}}-portable.zip"
+
+type assetNameData struct {
+	Version string
+	GOOS    string
+	GOARCH  string
+	EXT     string
+}
+
+// archiveExtFor returns the file extension a release is expected to use
+// for goos: a zip on Windows, a tar.gz everywhere else.
+func archiveExtFor(goos string) string {
+	if goos == "windows" {
+		return ".zip"
+	}
+	return ".tar.gz"
+}
+
+// renderAssetName expands an asset naming template for version on
+// goos/goarch.
+func renderAssetName(tmplStr, version, goos, goarch string) (string, error) {
+	tmpl, err := template.New("asset").Parse(tmplStr)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	data := assetNameData{Version: version, GOOS: goos, GOARCH: goarch, EXT: archiveExtFor(goos)}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// candidateAssetNames returns the asset names to look for, most preferred
+// first: the current naming template for the running platform, then the
+// legacy single-asset name for backward compatibility with older releases.
+func candidateAssetNames(version string) []string {
+	names := make([]string, 0, 2)
+	if name, err := renderAssetName(DefaultAssetNameTemplate, version, runtime.GOOS, runtime.GOARCH); err == nil {
+		names = append(names, name)
+	}
+	if name, err := renderAssetName(legacyAssetNameTemplate, version, runtime.GOOS, runtime.GOARCH); err == nil {
+		names = append(names, name)
+	}
+	return names
+}
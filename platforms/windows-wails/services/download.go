@@ -0,0 +1,204 @@
+package services
+
+// Staged, resumable download of an update asset. Progress is reported
+// incrementally and the SHA-256 digest is computed as the bytes stream to
+// disk, so InstallUpdate doesn't need a second pass over the file to verify
+// it.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DownloadProgress reports progress for an in-flight download.
+type DownloadProgress func(downloaded, total int64, bytesPerSecond float64)
+
+// DownloadUpdate downloads downloadURL to a temp file, resuming from a
+// partial ".part" file left by a previous interrupted attempt if one is
+// found. It honors ctx cancellation: cancelling aborts the in-flight
+// request but leaves the partial file in place so a later call can resume.
+// On success it returns the final file path and its SHA-256 digest.
+func (u *UpdaterService) DownloadUpdate(ctx context.Context, downloadURL string, progressCb DownloadProgress) (string, string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	u.downloadMu.Lock()
+	u.cancelDownload = cancel
+	u.downloadMu.Unlock()
+	defer func() {
+		u.downloadMu.Lock()
+		u.cancelDownload = nil
+		u.downloadMu.Unlock()
+		cancel()
+	}()
+
+	fileName := filepath.Base(downloadURL)
+	finalPath := filepath.Join(os.TempDir(), "fkey-update-"+fileName)
+	partPath := finalPath + ".part"
+
+	u.mu.Lock()
+	release := u.cachedRelease
+	u.mu.Unlock()
+	var token string
+	if release != nil && release.DownloadURL == downloadURL {
+		token = release.Token
+	}
+
+	hash := sha256.New()
+	var resumeFrom int64
+	if fi, err := os.Stat(partPath); err == nil {
+		resumeFrom = fi.Size()
+		if err := hashExistingFile(hash, partPath); err != nil {
+			// Corrupt or unreadable partial file - restart from scratch.
+			resumeFrom = 0
+			hash.Reset()
+			os.Remove(partPath)
+		}
+	}
+
+	resp, err := requestRange(ctx, downloadURL, resumeFrom, token)
+	if err != nil {
+		return "", "", fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && resumeFrom > 0 {
+		// Our .part file no longer matches the remote asset (a previous
+		// finalize failed, or the asset changed) - the Range the server
+		// once honored is now out of bounds. Discard it and restart clean
+		// rather than staying wedged on every retry.
+		resp.Body.Close()
+		os.Remove(partPath)
+		resumeFrom = 0
+		hash.Reset()
+
+		resp, err = requestRange(ctx, downloadURL, 0, token)
+		if err != nil {
+			return "", "", fmt.Errorf("download failed: %w", err)
+		}
+		defer resp.Body.Close()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// A full response discards whatever partial bytes we had, whether
+		// we didn't ask for a range or the server ignored one we sent
+		// (e.g. because the asset is now shorter than our .part file).
+		resumeFrom = 0
+		hash.Reset()
+	case http.StatusPartialContent:
+		// Server honored our Range request; resumeFrom stays as-is.
+	default:
+		return "", "", fmt.Errorf("download error: %d", resp.StatusCode)
+	}
+
+	total := resp.ContentLength
+	if total >= 0 {
+		total += resumeFrom
+	}
+
+	openFlags := os.O_WRONLY | os.O_CREATE
+	if resumeFrom > 0 {
+		openFlags |= os.O_APPEND
+	} else {
+		openFlags |= os.O_TRUNC
+	}
+	out, err := os.OpenFile(partPath, openFlags, 0644)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open temp file: %w", err)
+	}
+	defer out.Close()
+
+	tee := io.TeeReader(resp.Body, hash)
+
+	downloaded := resumeFrom
+	sessionStart := time.Now()
+	var sessionDownloaded int64
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, readErr := tee.Read(buf)
+		if n > 0 {
+			if _, werr := out.Write(buf[:n]); werr != nil {
+				return "", "", fmt.Errorf("failed to write temp file: %w", werr)
+			}
+			downloaded += int64(n)
+			sessionDownloaded += int64(n)
+			if progressCb != nil {
+				elapsed := time.Since(sessionStart).Seconds()
+				var bps float64
+				if elapsed > 0 {
+					bps = float64(sessionDownloaded) / elapsed
+				}
+				progressCb(downloaded, total, bps)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			// Leaves the .part file in place so a later call can resume,
+			// whether this was a cancellation or a transient network error.
+			return "", "", fmt.Errorf("download error: %w", readErr)
+		}
+	}
+
+	out.Close()
+
+	if err := os.Rename(partPath, finalPath); err != nil {
+		return "", "", fmt.Errorf("failed to finalize download: %w", err)
+	}
+
+	return finalPath, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// requestRange issues a GET for downloadURL, attaching a "Range:
+// bytes=<resumeFrom>-" header when resumeFrom is positive and an
+// Authorization header when token is set, so private forks and Enterprise
+// instances can resolve the asset the same way fetchChecksum resolves the
+// CHECKSUMS file.
+func requestRange(ctx context.Context, downloadURL string, resumeFrom int64, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	if resumeFrom > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeFrom))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{}
+	return client.Do(req)
+}
+
+// Cancel aborts the in-flight download started by DownloadUpdate, if any.
+// It is a no-op when no download is running.
+func (u *UpdaterService) Cancel() {
+	u.downloadMu.Lock()
+	defer u.downloadMu.Unlock()
+	if u.cancelDownload != nil {
+		u.cancelDownload()
+	}
+}
+
+// hashExistingFile feeds an already-downloaded partial file's bytes into
+// hash so resuming a download can continue the digest incrementally
+// instead of re-reading the whole file once it's complete.
+func hashExistingFile(hash io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(hash, f)
+	return err
+}
@@ -0,0 +1,267 @@
+package services
+
+// Pluggable release-source providers for the updater. A ReleaseSource knows
+// how to discover the latest published release for a given hosting backend;
+// UpdaterService is agnostic to which one it talks to.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ReleaseInfo is the backend-agnostic result of a release lookup.
+type ReleaseInfo struct {
+	Version      string // without leading "v"
+	ReleaseNotes string
+	AssetName    string
+	DownloadURL  string
+	ChecksumsURL string // sibling CHECKSUMS file, on the same host/repo as DownloadURL
+	ReleaseURL   string
+	AssetSize    int64
+	Token        string // credential (if any) required to fetch DownloadURL/ChecksumsURL
+}
+
+// ReleaseSource discovers the latest available release.
+type ReleaseSource interface {
+	FetchLatest() (*ReleaseInfo, error)
+}
+
+// githubToken resolves the token used to authenticate against GitHub, so
+// users on private forks or hitting rate limits can still update. A
+// configured token takes precedence; the GITHUB_TOKEN env var is the
+// fallback. An empty result means "no auth header".
+func githubToken(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	return os.Getenv("GITHUB_TOKEN")
+}
+
+// RawVersionSource is the original source: a single VERSION file served
+// from raw.githubusercontent.com, with the asset name and download URL
+// derived from the fixed naming pattern. It has no rate limit but cannot
+// provide release notes.
+type RawVersionSource struct {
+	Owner  string
+	Repo   string
+	Branch string
+	Token  string // optional; falls back to GITHUB_TOKEN env var
+	Client *http.Client
+}
+
+// NewRawVersionSource creates a RawVersionSource for owner/repo@branch.
+func NewRawVersionSource(owner, repo, branch string) *RawVersionSource {
+	return &RawVersionSource{
+		Owner:  owner,
+		Repo:   repo,
+		Branch: branch,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *RawVersionSource) FetchLatest() (*ReleaseInfo, error) {
+	url := fmt.Sprintf(VersionURL, s.Owner, s.Repo, s.Branch)
+	token := githubToken(s.Token)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check version: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, fmt.Errorf("version file not found")
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to fetch version: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version: %w", err)
+	}
+
+	version := strings.TrimPrefix(strings.TrimSpace(string(body)), "v")
+
+	return &ReleaseInfo{
+		Version:      version,
+		AssetName:    fmt.Sprintf("FKey-v%s-portable.zip", version),
+		DownloadURL:  fmt.Sprintf(DownloadURL, s.Owner, s.Repo, version, version),
+		ChecksumsURL: fmt.Sprintf(ChecksumsURL, s.Owner, s.Repo, version),
+		ReleaseURL:   fmt.Sprintf(ReleasePageURL, s.Owner, s.Repo, version),
+		Token:        token,
+	}, nil
+}
+
+// githubRelease is the subset of the GitHub "get the latest release"
+// response shape that the updater cares about.
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	Body    string        `json:"body"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+type githubAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	Size               int64  `json:"size"`
+}
+
+// fetchGitHubRelease GETs a "releases/latest" endpoint and decodes it,
+// attaching an Authorization header when token is non-empty.
+func fetchGitHubRelease(client *http.Client, apiURL, token string) (*githubRelease, error) {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check latest release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, fmt.Errorf("no releases found")
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to fetch latest release: %d", resp.StatusCode)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to decode release: %w", err)
+	}
+
+	return &release, nil
+}
+
+// selectAsset picks the asset matching the running platform, trying each
+// name in candidates in order. It returns nil rather than guessing when
+// nothing matches — installing a wrong-platform asset is worse than
+// reporting no update.
+func selectAsset(assets []githubAsset, candidates []string) *githubAsset {
+	for _, want := range candidates {
+		for i := range assets {
+			if assets[i].Name == want {
+				return &assets[i]
+			}
+		}
+	}
+	return nil
+}
+
+// findAssetByName looks up an asset by its exact name, e.g. the release's
+// "CHECKSUMS" sibling file.
+func findAssetByName(assets []githubAsset, name string) *githubAsset {
+	for i := range assets {
+		if assets[i].Name == name {
+			return &assets[i]
+		}
+	}
+	return nil
+}
+
+// GitHubReleasesSource talks to the public GitHub Releases API
+// (api.github.com), which also carries release notes and exact asset
+// metadata that the raw VERSION file cannot provide.
+type GitHubReleasesSource struct {
+	Owner  string
+	Repo   string
+	Token  string // optional; falls back to GITHUB_TOKEN env var
+	Client *http.Client
+}
+
+// NewGitHubReleasesSource creates a GitHubReleasesSource for owner/repo.
+func NewGitHubReleasesSource(owner, repo, token string) *GitHubReleasesSource {
+	return &GitHubReleasesSource{
+		Owner:  owner,
+		Repo:   repo,
+		Token:  token,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *GitHubReleasesSource) FetchLatest() (*ReleaseInfo, error) {
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", s.Owner, s.Repo)
+	return releaseInfoFromAPI(s.Client, apiURL, githubToken(s.Token), "https://github.com", s.Owner, s.Repo)
+}
+
+// GitHubEnterpriseSource is identical to GitHubReleasesSource but targets a
+// GitHub Enterprise instance's REST API root instead of api.github.com.
+type GitHubEnterpriseSource struct {
+	BaseURL string // e.g. "https://github.example.com"
+	Owner   string
+	Repo    string
+	Token   string // optional; falls back to GITHUB_TOKEN env var
+	Client  *http.Client
+}
+
+// NewGitHubEnterpriseSource creates a GitHubEnterpriseSource for owner/repo
+// hosted at baseURL (the instance's web root, not its API root).
+func NewGitHubEnterpriseSource(baseURL, owner, repo, token string) *GitHubEnterpriseSource {
+	return &GitHubEnterpriseSource{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Owner:   owner,
+		Repo:    repo,
+		Token:   token,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *GitHubEnterpriseSource) FetchLatest() (*ReleaseInfo, error) {
+	apiURL := fmt.Sprintf("%s/api/v3/repos/%s/%s/releases/latest", s.BaseURL, s.Owner, s.Repo)
+	return releaseInfoFromAPI(s.Client, apiURL, githubToken(s.Token), s.BaseURL, s.Owner, s.Repo)
+}
+
+// releaseInfoFromAPI fetches and adapts a releases/latest response shared
+// by both the public API and Enterprise sources. htmlBaseURL is the
+// instance's web root, used to build the human-facing release page link.
+func releaseInfoFromAPI(client *http.Client, apiURL, token, htmlBaseURL, owner, repo string) (*ReleaseInfo, error) {
+	release, err := fetchGitHubRelease(client, apiURL, token)
+	if err != nil {
+		return nil, err
+	}
+
+	version := strings.TrimPrefix(release.TagName, "v")
+	asset := selectAsset(release.Assets, candidateAssetNames(version))
+	if asset == nil {
+		return nil, fmt.Errorf("no release asset matches this platform (%s/%s)", runtime.GOOS, runtime.GOARCH)
+	}
+
+	checksumsURL := fmt.Sprintf("%s/%s/%s/releases/download/v%s/CHECKSUMS", htmlBaseURL, owner, repo, version)
+	if checksums := findAssetByName(release.Assets, "CHECKSUMS"); checksums != nil {
+		checksumsURL = checksums.BrowserDownloadURL
+	}
+
+	return &ReleaseInfo{
+		Version:      version,
+		ReleaseNotes: release.Body,
+		ReleaseURL:   fmt.Sprintf("%s/%s/%s/releases/tag/v%s", htmlBaseURL, owner, repo, version),
+		AssetName:    asset.Name,
+		DownloadURL:  asset.BrowserDownloadURL,
+		AssetSize:    asset.Size,
+		ChecksumsURL: checksumsURL,
+		Token:        token,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestSelectAsset(t *testing.T) {
+	assets := []githubAsset{
+		{Name: "FKey_1.4.0_darwin_arm64.zip"},
+		{Name: "FKey-v1.4.0-portable.zip"},
+	}
+
+	if got := selectAsset(assets, []string{"FKey_1.4.0_darwin_arm64.zip", "FKey-v1.4.0-portable.zip"}); got == nil || got.Name != "FKey_1.4.0_darwin_arm64.zip" {
+		t.Errorf("selectAsset did not prefer the first matching candidate, got %+v", got)
+	}
+
+	if got := selectAsset(assets, []string{"FKey-v1.4.0-portable.zip"}); got == nil || got.Name != "FKey-v1.4.0-portable.zip" {
+		t.Errorf("selectAsset did not fall through to the legacy name, got %+v", got)
+	}
+
+	if got := selectAsset(assets, []string{"FKey_1.4.0_windows_amd64.zip"}); got != nil {
+		t.Errorf("selectAsset should return nil for no match rather than guessing, got %+v", got)
+	}
+}
+
+func TestCandidateAssetNames(t *testing.T) {
+	names := candidateAssetNames("1.4.0")
+	if len(names) != 2 {
+		t.Fatalf("candidateAssetNames returned %d names, want 2", len(names))
+	}
+
+	ext := ".tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = ".zip"
+	}
+	want := "FKey_1.4.0_" + runtime.GOOS + "_" + runtime.GOARCH + ext
+	if names[0] != want {
+		t.Errorf("candidateAssetNames()[0] = %q, want %q", names[0], want)
+	}
+	if names[1] != "FKey-v1.4.0-portable.zip" {
+		t.Errorf("candidateAssetNames()[1] = %q, want the legacy asset name", names[1])
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+// Background update scheduler: periodically checks the configured
+// ReleaseSource without the frontend having to poll, and reports what it
+// finds through Wails runtime events so the UI can react.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	EventUpdateAvailable  = "update:available"
+	EventUpdateDownloaded = "update:downloaded"
+	EventUpdateError      = "update:error"
+)
+
+// SetContext attaches the Wails runtime context so the scheduler can emit
+// events to the frontend, then starts the background scheduler (unless the
+// persisted autoUpdate preference is "never"). Call this from the app's
+// Startup(ctx) hook. Starting the scheduler here, rather than in the
+// constructor, guarantees wailsCtx is already set before the first check
+// can possibly fire, so its update:available/update:error emit isn't
+// silently dropped.
+func (u *UpdaterService) SetContext(ctx context.Context) {
+	u.mu.Lock()
+	u.wailsCtx = ctx
+	mode := u.state.AutoUpdate
+	u.mu.Unlock()
+
+	if mode != AutoUpdateNever {
+		u.startScheduler()
+	}
+}
+
+// Shutdown stops the background scheduler, if running. Call this from the
+// app's Shutdown hook.
+func (u *UpdaterService) Shutdown() {
+	u.stopScheduler()
+}
+
+// GetAutoUpdateMode returns the user's current auto-update preference.
+func (u *UpdaterService) GetAutoUpdateMode() AutoUpdateMode {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.state.AutoUpdate
+}
+
+// SetAutoUpdateMode persists mode and starts or stops the background
+// scheduler to match it.
+func (u *UpdaterService) SetAutoUpdateMode(mode AutoUpdateMode) error {
+	u.mu.Lock()
+	u.state.AutoUpdate = mode
+	state := *u.state
+	u.mu.Unlock()
+
+	if err := state.save(); err != nil {
+		return fmt.Errorf("failed to save update preference: %w", err)
+	}
+
+	if mode == AutoUpdateNever {
+		u.stopScheduler()
+	} else {
+		u.startScheduler()
+	}
+
+	return nil
+}
+
+// SkipVersion records latestVersion so the scheduler won't keep notifying
+// the user about a release they've already declined.
+func (u *UpdaterService) SkipVersion(latestVersion string) error {
+	u.mu.Lock()
+	u.state.SkippedVersion = latestVersion
+	state := *u.state
+	u.mu.Unlock()
+
+	if err := state.save(); err != nil {
+		return fmt.Errorf("failed to save skipped version: %w", err)
+	}
+	return nil
+}
+
+// startScheduler launches the background ticker goroutine if it isn't
+// already running.
+func (u *UpdaterService) startScheduler() {
+	u.mu.Lock()
+	if u.schedDone != nil {
+		u.mu.Unlock()
+		return
+	}
+	done := make(chan struct{})
+	u.schedDone = done
+	lastCheck := u.lastCheck
+	u.mu.Unlock()
+
+	go func() {
+		// Catch up immediately if we've gone longer than CheckInterval
+		// without a check (e.g. the app was closed for several days);
+		// otherwise wait out the remainder before the first tick.
+		initialDelay := CheckInterval - time.Since(lastCheck)
+		if initialDelay < 0 {
+			initialDelay = 0
+		}
+
+		timer := time.NewTimer(initialDelay)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-timer.C:
+				u.runScheduledCheck(done)
+				timer.Reset(CheckInterval)
+			}
+		}
+	}()
+}
+
+// stopScheduler stops the background goroutine started by startScheduler,
+// if one is running.
+func (u *UpdaterService) stopScheduler() {
+	u.mu.Lock()
+	done := u.schedDone
+	u.schedDone = nil
+	u.mu.Unlock()
+
+	if done != nil {
+		close(done)
+	}
+}
+
+// runScheduledCheck performs one background check and reacts to the
+// result according to the user's AutoUpdateMode. stop is the scheduler's
+// done channel; closing it (via Shutdown/stopScheduler) cancels an
+// in-flight auto-download instead of leaving it running past shutdown.
+func (u *UpdaterService) runScheduledCheck(stop <-chan struct{}) {
+	info, err := u.CheckForUpdates(false)
+	if err != nil {
+		u.emitEvent(EventUpdateError, err.Error())
+		return
+	}
+
+	if !info.Available || info.LatestVersion == u.skippedVersion() {
+		return
+	}
+
+	u.emitEvent(EventUpdateAvailable, info)
+
+	if u.GetAutoUpdateMode() != AutoUpdateAuto {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	path, sha256sum, err := u.DownloadUpdate(ctx, info.DownloadURL, nil)
+	if err != nil {
+		u.emitEvent(EventUpdateError, err.Error())
+		return
+	}
+
+	u.emitEvent(EventUpdateDownloaded, map[string]string{
+		"path":    path,
+		"sha256":  sha256sum,
+		"version": info.LatestVersion,
+	})
+}
+
+func (u *UpdaterService) skippedVersion() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.state.SkippedVersion
+}
+
+// emitEvent forwards an event to the frontend if a Wails context has been
+// attached via SetContext; otherwise it's a no-op (e.g. in tests).
+func (u *UpdaterService) emitEvent(name string, data interface{}) {
+	u.mu.Lock()
+	ctx := u.wailsCtx
+	u.mu.Unlock()
+
+	if ctx != nil {
+		wailsruntime.EventsEmit(ctx, name, data)
+	}
+}
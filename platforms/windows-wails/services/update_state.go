@@ -0,0 +1,85 @@
+package services
+
+// Persisted updater state: the last time we checked for an update, the
+// user's auto-update preference, and any release they've chosen to skip.
+// Stored at <os.UserConfigDir()>/fkey/update-state.json so a restart
+// doesn't force an immediate re-check against GitHub.
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AutoUpdateMode controls how the background scheduler reacts to an
+// available update, mirroring the familiar "auto" / "prompt" / "never"
+// tri-state used by tools like hub's autoUpdate setting.
+type AutoUpdateMode string
+
+const (
+	AutoUpdateAuto   AutoUpdateMode = "auto"   // download in the background without asking; still waits for the user to apply it
+	AutoUpdatePrompt AutoUpdateMode = "prompt" // notify the frontend, let the user decide
+	AutoUpdateNever  AutoUpdateMode = "never"  // don't run background checks at all
+)
+
+// updateState is the on-disk shape of update-state.json.
+type updateState struct {
+	LastCheck      time.Time      `json:"lastCheck"`
+	AutoUpdate     AutoUpdateMode `json:"autoUpdate"`
+	SkippedVersion string         `json:"skippedVersion"`
+}
+
+// updateStatePath returns the path to the persisted state file.
+func updateStatePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fkey", "update-state.json"), nil
+}
+
+// loadUpdateState reads update-state.json, falling back to defaults
+// (AutoUpdatePrompt, never checked) when it's missing or unreadable.
+func loadUpdateState() *updateState {
+	defaults := &updateState{AutoUpdate: AutoUpdatePrompt}
+
+	path, err := updateStatePath()
+	if err != nil {
+		return defaults
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return defaults
+	}
+
+	var state updateState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return defaults
+	}
+	if state.AutoUpdate == "" {
+		state.AutoUpdate = AutoUpdatePrompt
+	}
+
+	return &state
+}
+
+// save writes the state back to update-state.json, creating its parent
+// directory if needed.
+func (s *updateState) save() error {
+	path, err := updateStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
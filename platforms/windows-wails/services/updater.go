@@ -4,7 +4,9 @@ package services
 // No API rate limits - fetches version from raw.githubusercontent.com
 
 import (
-	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,20 +15,25 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
-	GitHubOwner      = "miken90"
-	GitHubRepo       = "fkey"
-	GitHubBranch     = "main"
+	GitHubOwner  = "miken90"
+	GitHubRepo   = "fkey"
+	GitHubBranch = "main"
+	// Base name of the app's executable, excluding any OS-specific extension
+	BinaryBaseName = "fkey"
 	// Use raw.githubusercontent.com for version check (no rate limit)
-	VersionURL       = "https://raw.githubusercontent.com/%s/%s/%s/VERSION"
+	VersionURL = "https://raw.githubusercontent.com/%s/%s/%s/VERSION"
 	// Direct download URL for releases
-	DownloadURL      = "https://github.com/%s/%s/releases/download/v%s/FKey-v%s-portable.zip"
-	ReleasePageURL   = "https://github.com/%s/%s/releases/tag/v%s"
-	CheckInterval    = 24 * time.Hour // Check once per day
-	UserAgent        = "FKey-Updater/1.0"
+	DownloadURL = "https://github.com/%s/%s/releases/download/v%s/FKey-v%s-portable.zip"
+	// Sibling checksums file published alongside every release asset
+	ChecksumsURL   = "https://github.com/%s/%s/releases/download/v%s/CHECKSUMS"
+	ReleasePageURL = "https://github.com/%s/%s/releases/tag/v%s"
+	CheckInterval  = 24 * time.Hour // Check once per day
+	UserAgent      = "FKey-Updater/1.0"
 )
 
 // UpdateInfo contains information about an available update
@@ -44,87 +51,94 @@ type UpdateInfo struct {
 // UpdaterService manages auto-update checks
 type UpdaterService struct {
 	currentVersion string
-	lastCheck      time.Time
-	cachedInfo     *UpdateInfo
+	source         ReleaseSource
+
+	mu            sync.Mutex
+	lastCheck     time.Time
+	cachedInfo    *UpdateInfo
+	cachedRelease *ReleaseInfo
+	state         *updateState
+	wailsCtx      context.Context
+	schedDone     chan struct{}
+
+	downloadMu     sync.Mutex
+	cancelDownload context.CancelFunc
 }
 
-// NewUpdaterService creates a new updater service
+// NewUpdaterService creates a new updater service backed by the default
+// raw-VERSION-file source against GitHubOwner/GitHubRepo.
 func NewUpdaterService(currentVersion string) *UpdaterService {
+	return NewUpdaterServiceWithSource(currentVersion, NewRawVersionSource(GitHubOwner, GitHubRepo, GitHubBranch))
+}
+
+// NewUpdaterServiceWithSource creates an updater service backed by a custom
+// ReleaseSource, e.g. the GitHub Releases API or a GitHub Enterprise instance.
+// Unless the persisted autoUpdate preference is "never", the background
+// scheduler that periodically checks for updates starts once SetContext is
+// called, not here — starting it before then risks an update:available or
+// update:error from the very first check being silently dropped, since
+// emitEvent is a no-op until a Wails context is attached.
+func NewUpdaterServiceWithSource(currentVersion string, source ReleaseSource) *UpdaterService {
+	state := loadUpdateState()
+
 	return &UpdaterService{
 		currentVersion: currentVersion,
+		source:         source,
+		lastCheck:      state.LastCheck,
+		state:          state,
 	}
 }
 
-// CheckForUpdates checks GitHub for a newer version
+// CheckForUpdates checks the configured ReleaseSource for a newer version
 func (u *UpdaterService) CheckForUpdates(force bool) (*UpdateInfo, error) {
-	// Use cache if checked recently (unless forced)
+	u.mu.Lock()
 	if !force && u.cachedInfo != nil && time.Since(u.lastCheck) < CheckInterval {
-		return u.cachedInfo, nil
+		info := u.cachedInfo
+		u.mu.Unlock()
+		return info, nil
 	}
+	u.mu.Unlock()
 
-	latestVersion, err := u.fetchLatestVersion()
+	release, err := u.source.FetchLatest()
 	if err != nil {
 		return nil, err
 	}
 
-	info := u.buildUpdateInfo(latestVersion)
+	info := u.buildUpdateInfo(release)
+
+	u.mu.Lock()
 	u.cachedInfo = info
+	u.cachedRelease = release
 	u.lastCheck = time.Now()
-
-	return info, nil
-}
-
-// fetchLatestVersion gets the latest version from VERSION file (no rate limit)
-func (u *UpdaterService) fetchLatestVersion() (string, error) {
-	url := fmt.Sprintf(VersionURL, GitHubOwner, GitHubRepo, GitHubBranch)
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("User-Agent", UserAgent)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to check version: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == 404 {
-		return "", fmt.Errorf("version file not found")
+	u.state.LastCheck = u.lastCheck
+	state := *u.state
+	u.mu.Unlock()
+
+	if err := state.save(); err != nil {
+		// Non-fatal: the check result is still valid, we just won't
+		// remember it across restarts.
+		fmt.Printf("fkey: failed to persist update state: %v\n", err)
 	}
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to fetch version: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read version: %w", err)
-	}
-
-	version := strings.TrimSpace(string(body))
-	return version, nil
+	return info, nil
 }
 
-// buildUpdateInfo creates UpdateInfo from version string
-func (u *UpdaterService) buildUpdateInfo(latestVersion string) *UpdateInfo {
-	// Strip 'v' prefix if present for consistency
-	latest := strings.TrimPrefix(latestVersion, "v")
-	
+// buildUpdateInfo adapts a ReleaseInfo from the ReleaseSource into the
+// UpdateInfo shape the rest of the app (and the frontend) consumes.
+func (u *UpdaterService) buildUpdateInfo(release *ReleaseInfo) *UpdateInfo {
 	info := &UpdateInfo{
 		CurrentVersion: u.currentVersion,
-		LatestVersion:  "v" + latest,
-		ReleaseURL:     fmt.Sprintf(ReleasePageURL, GitHubOwner, GitHubRepo, latest),
-		DownloadURL:    fmt.Sprintf(DownloadURL, GitHubOwner, GitHubRepo, latest, latest),
-		AssetName:      fmt.Sprintf("FKey-v%s-portable.zip", latest),
+		LatestVersion:  "v" + release.Version,
+		ReleaseNotes:   release.ReleaseNotes,
+		ReleaseURL:     release.ReleaseURL,
+		DownloadURL:    release.DownloadURL,
+		AssetName:      release.AssetName,
+		AssetSize:      release.AssetSize,
 	}
 
 	// Compare versions
 	current := strings.TrimPrefix(u.currentVersion, "v")
-	info.Available = u.IsNewerVersion(current, latest)
+	info.Available = u.IsNewerVersion(current, release.Version)
 
 	return info
 }
@@ -134,7 +148,7 @@ func (u *UpdaterService) IsNewerVersion(current, latest string) bool {
 	// Strip 'v' prefix if present
 	current = strings.TrimPrefix(current, "v")
 	latest = strings.TrimPrefix(latest, "v")
-	
+
 	// Remove any suffix like "-wails", "-beta", etc. for comparison
 	current = strings.Split(current, "-")[0]
 	latest = strings.Split(latest, "-")[0]
@@ -162,114 +176,250 @@ func (u *UpdaterService) IsNewerVersion(current, latest string) bool {
 	return false
 }
 
-// DownloadUpdate downloads the update to temp directory
-func (u *UpdaterService) DownloadUpdate(downloadURL string, progressCb func(downloaded, total int64)) (string, error) {
-	client := &http.Client{Timeout: 5 * time.Minute}
-	req, err := http.NewRequest("GET", downloadURL, nil)
+// OpenReleasePage opens the release page in browser
+func (u *UpdaterService) OpenReleasePage(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// GetCurrentVersion returns the current version
+func (u *UpdaterService) GetCurrentVersion() string {
+	return u.currentVersion
+}
+
+// InstallUpdate verifies, extracts and installs the update in place.
+// The downloaded asset's checksum is fetched from the release's CHECKSUMS
+// file and checked before anything is extracted or applied. downloadedSHA256
+// is the digest DownloadUpdate already computed while streaming the file to
+// disk; when set, it is compared directly instead of re-hashing zipPath. Pass
+// an empty string to force a full re-read of zipPath (e.g. for a file that
+// wasn't fetched through DownloadUpdate).
+func (u *UpdaterService) InstallUpdate(zipPath, downloadedSHA256 string) error {
+	u.mu.Lock()
+	release := u.cachedRelease
+	u.mu.Unlock()
+
+	if release == nil {
+		return fmt.Errorf("no update info available, call CheckForUpdates first")
+	}
+
+	expectedSum, err := fetchChecksum(release.ChecksumsURL, release.AssetName, release.Token)
+	if err != nil {
+		return fmt.Errorf("failed to fetch checksum: %w", err)
+	}
+
+	if downloadedSHA256 != "" {
+		if !strings.EqualFold(downloadedSHA256, strings.TrimSpace(expectedSum)) {
+			return fmt.Errorf("checksum verification failed: expected %s, got %s", expectedSum, downloadedSHA256)
+		}
+	} else if err := u.VerifyChecksum(zipPath, expectedSum); err != nil {
+		return fmt.Errorf("checksum verification failed: %w", err)
+	}
+
+	// Extract the downloaded asset (zip, tar.gz or bare binary) to temp
+	extractDir := filepath.Join(os.TempDir(), "fkey-update-extract")
+	os.RemoveAll(extractDir)
+	os.MkdirAll(extractDir, 0755)
+
+	if err := u.extractArchive(zipPath, extractDir); err != nil {
+		return fmt.Errorf("failed to extract update: %w", err)
+	}
+
+	newExePath, err := findExtractedBinary(extractDir)
+	if err != nil {
+		return err
+	}
+
+	if err := u.ApplyUpdate(newExePath); err != nil {
+		return fmt.Errorf("failed to apply update: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyChecksum computes the SHA-256 checksum of the file at path and
+// compares it against expected (a hex-encoded digest, case-insensitive).
+func (u *UpdaterService) VerifyChecksum(path, expected string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if actual != expected {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+	}
+
+	return nil
+}
+
+// fetchChecksum downloads a release's CHECKSUMS file (at checksumsURL, the
+// same host/repo as the asset itself) and returns the hex digest recorded
+// for assetName, attaching token as a Bearer credential when set so
+// private forks and Enterprise instances resolve it too. The file is
+// expected to contain one "<hexdigest>  <filename>" pair per line,
+// matching `sha256sum` output.
+func fetchChecksum(checksumsURL, assetName, token string) (string, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	req, err := http.NewRequest("GET", checksumsURL, nil)
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("User-Agent", UserAgent)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("download failed: %w", err)
+		return "", fmt.Errorf("failed to fetch checksums: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("download error: %d", resp.StatusCode)
+		return "", fmt.Errorf("failed to fetch checksums: %d", resp.StatusCode)
 	}
 
-	// Create temp file
-	tempDir := os.TempDir()
-	fileName := filepath.Base(downloadURL)
-	tempFile := filepath.Join(tempDir, "fkey-update-"+fileName)
-
-	out, err := os.Create(tempFile)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
+		return "", fmt.Errorf("failed to read checksums: %w", err)
 	}
-	defer out.Close()
 
-	// Download with progress
-	var downloaded int64
-	total := resp.ContentLength
-	buf := make([]byte, 32*1024)
-
-	for {
-		n, err := resp.Body.Read(buf)
-		if n > 0 {
-			out.Write(buf[:n])
-			downloaded += int64(n)
-			if progressCb != nil {
-				progressCb(downloaded, total)
-			}
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
 		}
-		if err == io.EOF {
-			break
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
 		}
-		if err != nil {
-			return "", fmt.Errorf("download error: %w", err)
+		if strings.TrimPrefix(fields[1], "*") == assetName {
+			return fields[0], nil
 		}
 	}
 
-	return tempFile, nil
+	return "", fmt.Errorf("no checksum entry for %s", assetName)
 }
 
-// OpenReleasePage opens the release page in browser
-func (u *UpdaterService) OpenReleasePage(url string) error {
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
-	case "darwin":
-		cmd = exec.Command("open", url)
-	default:
-		cmd = exec.Command("xdg-open", url)
+// ApplyUpdate replaces the running executable with newExe.
+//
+// On Unix, the OS allows a running binary to be unlinked and replaced, so
+// this happens in-process: the current exe is moved aside to "<exe>.old",
+// newExe is moved into its place, and on any failure the original exe is
+// rolled back from "<exe>.old".
+//
+// On Windows the running exe is locked, so the swap is deferred to a batch
+// script that waits for this process to exit before replacing it; the
+// script is written and launched here so callers only need to exit the app
+// afterwards.
+func (u *UpdaterService) ApplyUpdate(newExe string) error {
+	currentExe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	currentExe, err = filepath.Abs(currentExe)
+	if err != nil {
+		return fmt.Errorf("failed to resolve executable path: %w", err)
 	}
-	return cmd.Start()
-}
 
-// GetCurrentVersion returns the current version
-func (u *UpdaterService) GetCurrentVersion() string {
-	return u.currentVersion
+	if runtime.GOOS == "windows" {
+		return u.applyUpdateWindows(currentExe, newExe)
+	}
+	return u.applyUpdateUnix(currentExe, newExe)
 }
 
-// InstallUpdate extracts and installs the update, then restarts the app
-// Returns the path to the batch script that will perform the update
-func (u *UpdaterService) InstallUpdate(zipPath string) (string, error) {
-	// Get current exe path
-	currentExe, err := os.Executable()
-	if err != nil {
-		return "", fmt.Errorf("failed to get executable path: %w", err)
+// applyUpdateUnix performs an atomic, in-process swap with rollback.
+//
+// newExe typically lives under os.TempDir(), which is frequently a separate
+// filesystem (tmpfs) from currentExe's directory, and os.Rename cannot cross
+// a filesystem boundary (EXDEV). So newExe is first staged into currentExe's
+// own directory — renaming it there is either a same-filesystem rename or,
+// failing that, a stream copy followed by removing the original — and only
+// that staged copy is ever renamed onto currentExe.
+func (u *UpdaterService) applyUpdateUnix(currentExe, newExe string) error {
+	stagedExe := filepath.Join(filepath.Dir(currentExe), "."+filepath.Base(currentExe)+".new")
+	os.Remove(stagedExe)
+	if err := renameOrCopy(newExe, stagedExe); err != nil {
+		return fmt.Errorf("failed to stage update alongside executable: %w", err)
 	}
-	currentExe, _ = filepath.Abs(currentExe)
-	
-	// Extract zip to temp
-	extractDir := filepath.Join(os.TempDir(), "fkey-update-extract")
-	os.RemoveAll(extractDir)
-	os.MkdirAll(extractDir, 0755)
-	
-	if err := u.extractZip(zipPath, extractDir); err != nil {
-		return "", fmt.Errorf("failed to extract update: %w", err)
-	}
-	
-	// Find new exe in extracted files
-	var newExePath string
-	filepath.Walk(extractDir, func(path string, info os.FileInfo, err error) error {
-		if err == nil && !info.IsDir() && strings.EqualFold(filepath.Ext(path), ".exe") {
-			newExePath = path
-			return filepath.SkipDir
+	if err := os.Chmod(stagedExe, 0755); err != nil {
+		os.Remove(stagedExe)
+		return fmt.Errorf("failed to set executable permissions: %w", err)
+	}
+
+	oldExe := currentExe + ".old"
+	os.Remove(oldExe)
+
+	if err := os.Rename(currentExe, oldExe); err != nil {
+		os.Remove(stagedExe)
+		return fmt.Errorf("failed to back up current executable: %w", err)
+	}
+
+	if err := os.Rename(stagedExe, currentExe); err != nil {
+		// Roll back
+		if rbErr := os.Rename(oldExe, currentExe); rbErr != nil {
+			return fmt.Errorf("failed to install update (%v) and rollback failed (%v)", err, rbErr)
 		}
+		return fmt.Errorf("failed to install update, rolled back: %w", err)
+	}
+
+	os.Remove(oldExe)
+	return nil
+}
+
+// renameOrCopy moves src to dst. It tries os.Rename first (the common case,
+// when both live on the same filesystem) and falls back to a stream copy
+// plus removing src when the rename fails — notably when src and dst are on
+// different filesystems (EXDEV), which os.Rename can't cross. The errno
+// isn't inspected since it's OS-specific; any rename failure is worth
+// retrying as a copy.
+func renameOrCopy(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
 		return nil
-	})
-	
-	if newExePath == "" {
-		return "", fmt.Errorf("no exe found in update package")
 	}
-	
-	// Create batch script to replace exe after app exits
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	os.Remove(src)
+	return nil
+}
+
+// applyUpdateWindows writes a batch script that swaps the exe once this
+// process has exited, then launches it detached.
+func (u *UpdaterService) applyUpdateWindows(currentExe, newExe string) error {
 	batchPath := filepath.Join(os.TempDir(), "fkey-updater.bat")
 	batchContent := fmt.Sprintf(`@echo off
 echo Updating FKey...
@@ -287,71 +437,49 @@ if errorlevel 1 (
     exit /b 1
 )
 start "" "%s"
-del "%s" > nul 2>&1
-rmdir /s /q "%s" > nul 2>&1
 del "%%~f0"
-`, currentExe, currentExe, newExePath, currentExe, currentExe, zipPath, extractDir)
-	
+`, currentExe, currentExe, newExe, currentExe, currentExe)
+
 	if err := os.WriteFile(batchPath, []byte(batchContent), 0755); err != nil {
-		return "", fmt.Errorf("failed to create updater script: %w", err)
+		return fmt.Errorf("failed to create updater script: %w", err)
 	}
-	
-	return batchPath, nil
-}
 
-// RunUpdateScript runs the update batch script and signals app to exit
-func (u *UpdaterService) RunUpdateScript(batchPath string) error {
-	// Use cmd /c with quoted path to handle spaces
 	cmd := exec.Command("cmd", "/c", batchPath)
 	cmd.Dir = filepath.Dir(batchPath)
-	return cmd.Start()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to launch updater script: %w", err)
+	}
+
+	return nil
 }
 
-// extractZip extracts a zip file to destination directory
-func (u *UpdaterService) extractZip(src, dst string) error {
-	r, err := zip.OpenReader(src)
-	if err != nil {
-		return err
+// findExtractedBinary locates the update's executable inside an extracted
+// package: "<BinaryBaseName>.exe" on Windows, or the bare "<BinaryBaseName>"
+// on Unix. copyBareBinary names a wrapper-less asset this way, and archive
+// builds are expected to ship their binary under this name too.
+func findExtractedBinary(dir string) (string, error) {
+	wantName := BinaryBaseName
+	if runtime.GOOS == "windows" {
+		wantName += ".exe"
 	}
-	defer r.Close()
-	
-	for _, f := range r.File {
-		// Prevent zip slip
-		name := filepath.Base(f.Name)
-		if name == "" || strings.HasPrefix(name, ".") {
-			continue
-		}
-		
-		fpath := filepath.Join(dst, name)
-		
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(fpath, 0755)
-			continue
-		}
-		
-		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
-			return err
-		}
-		
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return err
+
+	var found string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
 		}
-		
-		rc, err := f.Open()
-		if err != nil {
-			outFile.Close()
-			return err
+		if strings.EqualFold(filepath.Base(path), wantName) {
+			found = path
+			return filepath.SkipDir
 		}
-		
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
-		
-		if err != nil {
-			return err
+		if runtime.GOOS == "windows" && strings.EqualFold(filepath.Ext(path), ".exe") {
+			found = path
 		}
+		return nil
+	})
+
+	if found == "" {
+		return "", fmt.Errorf("no executable found in update package")
 	}
-	
-	return nil
+	return found, nil
 }
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	u := &UpdaterService{}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "asset.bin")
+	if err := os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	// sha256("hello world")
+	const want = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+	if err := u.VerifyChecksum(path, want); err != nil {
+		t.Fatalf("VerifyChecksum(matching) returned error: %v", err)
+	}
+	if err := u.VerifyChecksum(path, "0000000000000000000000000000000000000000000000000000000000000000"); err == nil {
+		t.Fatal("VerifyChecksum(mismatch) expected an error, got nil")
+	}
+	if err := u.VerifyChecksum(filepath.Join(dir, "missing.bin"), want); err == nil {
+		t.Fatal("VerifyChecksum(missing file) expected an error, got nil")
+	}
+}
+
+func TestFetchChecksum(t *testing.T) {
+	const body = "b94d27b9934d3e08a52e52d7da7dacefbe65f0f8a3bab6cf93dfb80a1c1f6b4  FKey_1.4.0_linux_amd64.tar.gz\n" +
+		"deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef  FKey_1.4.0_windows_amd64.zip\n"
+
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	sum, err := fetchChecksum(srv.URL, "FKey_1.4.0_linux_amd64.tar.gz", "s3cr3t")
+	if err != nil {
+		t.Fatalf("fetchChecksum returned error: %v", err)
+	}
+	if sum != "b94d27b9934d3e08a52e52d7da7dacefbe65f0f8a3bab6cf93dfb80a1c1f6b4" {
+		t.Errorf("fetchChecksum returned %q", sum)
+	}
+	if gotAuth != "Bearer s3cr3t" {
+		t.Errorf("fetchChecksum sent Authorization %q, want %q", gotAuth, "Bearer s3cr3t")
+	}
+
+	if _, err := fetchChecksum(srv.URL, "no-such-asset.zip", ""); err == nil {
+		t.Fatal("fetchChecksum(unknown asset) expected an error, got nil")
+	}
+}